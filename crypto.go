@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// kontainedKeyEnv holds a hex-encoded AES-256 key directly; kontainedKeyFileEnv
+// points at a file containing the same. Either lets a user ship secrets
+// inside the binary's payload without leaving the key itself on disk next
+// to the extracted files.
+const (
+	kontainedKeyEnv     = "KONTAINED_KEY"
+	kontainedKeyFileEnv = "KONTAINED_KEY_FILE"
+)
+
+// loadEncryptionKey resolves the AES-256 key used to encrypt and decrypt
+// payload entries. It returns (nil, nil) if neither env var is set, which
+// means encryption is simply not in use for this payload.
+func loadEncryptionKey() ([]byte, error) {
+	if raw := os.Getenv(kontainedKeyEnv); raw != "" {
+		return decodeKey(raw, kontainedKeyEnv)
+	}
+
+	if path := os.Getenv(kontainedKeyFileEnv); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", kontainedKeyFileEnv, err)
+		}
+		return decodeKey(strings.TrimSpace(string(raw)), kontainedKeyFileEnv)
+	}
+
+	return nil, nil
+}
+
+func decodeKey(hexKey, source string) ([]byte, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid hex: %v", source, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s must decode to 32 bytes for AES-256, got %d", source, len(key))
+	}
+	return key, nil
+}
+
+// encryptEntry seals plaintext under a freshly generated nonce, returning
+// nonce||ciphertext so decryptEntry can recover both from a single blob.
+func encryptEntry(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decryptEntry reverses encryptEntry, authenticating the ciphertext as it
+// goes: a tampered entry fails here with an error instead of returning
+// corrupted plaintext.
+func decryptEntry(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted entry is shorter than a nonce")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt entry (wrong key or tampered data): %v", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
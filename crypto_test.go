@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptDecryptEntryRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+	plaintext := []byte("project.db contents that must stay secret")
+
+	ciphertext, err := encryptEntry(key, plaintext)
+	if err != nil {
+		t.Fatalf("encryptEntry failed: %v", err)
+	}
+	if bytes.Equal(ciphertext, plaintext) {
+		t.Fatal("ciphertext must not equal plaintext")
+	}
+
+	got, err := decryptEntry(key, ciphertext)
+	if err != nil {
+		t.Fatalf("decryptEntry failed: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decryptEntry = %q, want %q", got, plaintext)
+	}
+}
+
+func TestDecryptEntryRejectsTamperedCiphertext(t *testing.T) {
+	key := make([]byte, 32)
+	ciphertext, err := encryptEntry(key, []byte("hello"))
+	if err != nil {
+		t.Fatalf("encryptEntry failed: %v", err)
+	}
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	if _, err := decryptEntry(key, tampered); err == nil {
+		t.Error("expected decryptEntry to reject tampered ciphertext")
+	}
+}
+
+func TestDecryptEntryRejectsWrongKey(t *testing.T) {
+	key := make([]byte, 32)
+	other := make([]byte, 32)
+	other[0] = 1
+
+	ciphertext, err := encryptEntry(key, []byte("hello"))
+	if err != nil {
+		t.Fatalf("encryptEntry failed: %v", err)
+	}
+
+	if _, err := decryptEntry(other, ciphertext); err == nil {
+		t.Error("expected decryptEntry to reject the wrong key")
+	}
+}
+
+func TestDecodeKeyValidatesLength(t *testing.T) {
+	if _, err := decodeKey("not-hex", kontainedKeyEnv); err == nil {
+		t.Error("expected decodeKey to reject non-hex input")
+	}
+	if _, err := decodeKey("aabbcc", kontainedKeyEnv); err == nil {
+		t.Error("expected decodeKey to reject a key that isn't 32 bytes")
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/sha256"
-	"embed"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -22,39 +22,63 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed assets/*
-var assets embed.FS
-
-// Embedded binaries for different platforms
-//
-//go:embed binaries/node-linux-x64
-//go:embed binaries/node-linux-arm64
-//go:embed binaries/node-darwin-x64
-//go:embed binaries/node-darwin-arm64
-//go:embed binaries/node-windows-x64.exe
-//go:embed binaries/node-windows-arm64.exe
-var nodeBinaries embed.FS
-
-// Embedded dev server and dependencies
-//
-//go:embed dev-server.ts
-//go:embed node_modules.tar.gz
-//go:embed project.db
-var projectFiles embed.FS
-
 type DevEnvironment struct {
-	tempDir     string
-	nodeExePath string
-	serverPath  string
-	dbPath      string
-	nodeModules string
-	originalBin string
-	dbModified  bool
-	ctx         context.Context
-	cancel      context.CancelFunc
+	tempDir      string
+	nodeExePath  string
+	serverPath   string
+	dbPath       string
+	nodeModules  string
+	originalBin  string
+	dbModified   bool
+	lastDBHash   string
+	payload      *Payload
+	triggers     *TriggerConfig
+	state        StateBackend
+	devServerCmd *exec.Cmd
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// nodeBinaryEntry returns the payload entry name for the Node.js binary
+// matching the current platform.
+func nodeBinaryEntry() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if runtime.GOARCH == "arm64" {
+			return "node-linux-arm64", nil
+		}
+		return "node-linux-x64", nil
+	case "darwin":
+		if runtime.GOARCH == "arm64" {
+			return "node-darwin-arm64", nil
+		}
+		return "node-darwin-x64", nil
+	case "windows":
+		if runtime.GOARCH == "arm64" {
+			return "node-windows-arm64.exe", nil
+		}
+		return "node-windows-x64.exe", nil
+	default:
+		return "", fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "pack" {
+		if err := runPack(os.Args[2:]); err != nil {
+			log.Fatalf("❌ pack failed: %v", err)
+		}
+		return
+	}
+
+	// Internal re-exec entrypoint: applySandbox re-execs this same binary
+	// with this marker so namespace setup (pivot_root, /proc, capabilities,
+	// seccomp) happens inside the new namespaces before node ever runs.
+	if len(os.Args) > 1 && os.Args[1] == "--sandbox-init" {
+		sandboxInit(os.Args[2:])
+		os.Exit(1) // sandboxInit only returns on failure; it execs into node on success
+	}
+
 	fmt.Println("🚀 Starting self-contained portable dev environment...")
 
 	env, err := NewDevEnvironment()
@@ -82,7 +106,7 @@ func main() {
 	<-env.ctx.Done()
 }
 
-func NewDevEnvironment() (*DevEnvironment, error) {
+func NewDevEnvironment() (env *DevEnvironment, err error) {
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "portable-dev-*")
 	if err != nil {
@@ -92,6 +116,11 @@ func NewDevEnvironment() (*DevEnvironment, error) {
 	fmt.Printf("📁 Created workspace: %s\n", tempDir)
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
 
 	// Get the current executable path for repacking
 	originalBin, err := os.Executable()
@@ -99,9 +128,27 @@ func NewDevEnvironment() (*DevEnvironment, error) {
 		return nil, fmt.Errorf("failed to get executable path: %v", err)
 	}
 
+	payload, err := OpenPayload(originalBin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open appended payload: %v", err)
+	}
+
+	triggers, err := loadTriggerConfig(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trigger config: %v", err)
+	}
+
+	state, err := newStateBackend()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure state backend: %v", err)
+	}
+
 	return &DevEnvironment{
 		tempDir:     tempDir,
 		originalBin: originalBin,
+		payload:     payload,
+		triggers:    triggers,
+		state:       state,
 		ctx:         ctx,
 		cancel:      cancel,
 	}, nil
@@ -143,38 +190,17 @@ func (env *DevEnvironment) Start() error {
 }
 
 func (env *DevEnvironment) extractNodeBinary() error {
-	var binaryPath string
-
-	// Determine the correct Node.js binary for current platform
-	switch runtime.GOOS {
-	case "linux":
-		if runtime.GOARCH == "arm64" {
-			binaryPath = "binaries/node-linux-arm64"
-		} else {
-			binaryPath = "binaries/node-linux-x64"
-		}
-	case "darwin":
-		if runtime.GOARCH == "arm64" {
-			binaryPath = "binaries/node-darwin-arm64"
-		} else {
-			binaryPath = "binaries/node-darwin-x64"
-		}
-	case "windows":
-		if runtime.GOARCH == "arm64" {
-			binaryPath = "binaries/node-windows-arm64.exe"
-		} else {
-			binaryPath = "binaries/node-windows-x64.exe"
-		}
-	default:
-		return fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
+	entryName, err := nodeBinaryEntry()
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("🔧 Extracting Node.js for %s/%s\n", runtime.GOOS, runtime.GOARCH)
 
-	// Read embedded binary
-	nodeData, err := nodeBinaries.ReadFile(binaryPath)
+	// Read Node binary from the appended payload
+	nodeData, err := env.payload.ReadEntry(entryName)
 	if err != nil {
-		return fmt.Errorf("failed to read embedded Node binary: %v", err)
+		return fmt.Errorf("failed to read Node binary from payload: %v", err)
 	}
 
 	// Write to temp directory
@@ -194,12 +220,13 @@ func (env *DevEnvironment) extractNodeBinary() error {
 func (env *DevEnvironment) extractDatabase() error {
 	fmt.Println("💾 Extracting SQLite database...")
 
-	dbData, err := projectFiles.ReadFile("project.db")
+	env.dbPath = filepath.Join(env.tempDir, "project.db")
+
+	dbData, err := env.loadDatabaseBytes()
 	if err != nil {
-		return fmt.Errorf("failed to read embedded database: %v", err)
+		return err
 	}
 
-	env.dbPath = filepath.Join(env.tempDir, "project.db")
 	if err := os.WriteFile(env.dbPath, dbData, 0644); err != nil {
 		return fmt.Errorf("failed to write database: %v", err)
 	}
@@ -208,13 +235,37 @@ func (env *DevEnvironment) extractDatabase() error {
 	return nil
 }
 
+// loadDatabaseBytes prefers the configured remote state backend, if any,
+// falling back to the database embedded in the payload when the backend
+// has nothing stored yet.
+func (env *DevEnvironment) loadDatabaseBytes() ([]byte, error) {
+	if env.state != nil {
+		data, err := env.state.Load(env.ctx)
+		switch {
+		case err == nil:
+			fmt.Println("☁️  Loaded database from remote state backend")
+			return data, nil
+		case errors.Is(err, ErrStateNotFound):
+			fmt.Println("☁️  No remote state found yet, falling back to embedded database")
+		default:
+			return nil, fmt.Errorf("failed to load database from state backend: %v", err)
+		}
+	}
+
+	dbData, err := env.payload.ReadEntry("project.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database from payload: %v", err)
+	}
+	return dbData, nil
+}
+
 func (env *DevEnvironment) extractNodeModules() error {
 	fmt.Println("📦 Extracting node_modules...")
 
 	// Read compressed node_modules
-	nodeModulesData, err := projectFiles.ReadFile("node_modules.tar.gz")
+	nodeModulesData, err := env.payload.ReadEntry("node_modules.tar.gz")
 	if err != nil {
-		return fmt.Errorf("failed to read embedded node_modules: %v", err)
+		return fmt.Errorf("failed to read node_modules from payload: %v", err)
 	}
 
 	env.nodeModules = filepath.Join(env.tempDir, "node_modules")
@@ -275,9 +326,9 @@ func (env *DevEnvironment) extractNodeModules() error {
 func (env *DevEnvironment) extractDevServer() error {
 	fmt.Println("🖥️  Extracting dev server...")
 
-	serverData, err := projectFiles.ReadFile("dev-server.ts")
+	serverData, err := env.payload.ReadEntry("dev-server.ts")
 	if err != nil {
-		return fmt.Errorf("failed to read embedded dev server: %v", err)
+		return fmt.Errorf("failed to read dev server from payload: %v", err)
 	}
 
 	env.serverPath = filepath.Join(env.tempDir, "dev-server.ts")
@@ -297,42 +348,38 @@ func (env *DevEnvironment) startDevServer() error {
 	cmd.Dir = env.tempDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	cmd.Env = env.devServerEnv()
 
-	// Set NODE_PATH to our extracted node_modules
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("NODE_PATH=%s", env.nodeModules),
-		fmt.Sprintf("PATH=%s%c%s", filepath.Dir(env.nodeExePath), os.PathListSeparator, os.Getenv("PATH")),
-	)
+	if sandboxEnabled() {
+		if err := applySandbox(cmd, env); err != nil {
+			return fmt.Errorf("failed to configure sandbox: %v", err)
+		}
+	}
 
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start dev server: %v", err)
 	}
+	env.devServerCmd = cmd
+	reapWhenDone(cmd)
 
-	// Monitor for database changes
-	go env.monitorDatabaseChanges()
+	// Supervise the workspace: hash project.db on real write events instead
+	// of polling, and run any kontained.yaml triggers whose patterns match.
+	if err := env.superviseDevServer(); err != nil {
+		return fmt.Errorf("failed to start file watcher: %v", err)
+	}
 
 	fmt.Println("✅ Development server started")
 	return nil
 }
 
-func (env *DevEnvironment) monitorDatabaseChanges() {
-	initialHash := env.getDatabaseHash()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-env.ctx.Done():
-			return
-		case <-ticker.C:
-			currentHash := env.getDatabaseHash()
-			if currentHash != initialHash {
-				env.dbModified = true
-				initialHash = currentHash
-			}
-		}
-	}
+// devServerEnv builds the environment passed to the Node.js dev server and
+// to any trigger command: NODE_PATH points at the extracted node_modules,
+// and PATH is extended so the extracted node binary is found first.
+func (env *DevEnvironment) devServerEnv() []string {
+	return append(os.Environ(),
+		fmt.Sprintf("NODE_PATH=%s", env.nodeModules),
+		fmt.Sprintf("PATH=%s%c%s", filepath.Dir(env.nodeExePath), os.PathListSeparator, os.Getenv("PATH")),
+	)
 }
 
 func (env *DevEnvironment) getDatabaseHash() string {
@@ -374,32 +421,3 @@ func (env *DevEnvironment) Shutdown() error {
 	fmt.Println("👋 Goodbye!")
 	return nil
 }
-
-func (env *DevEnvironment) repackBinary() error {
-	// This is a simplified version - you'd need a more sophisticated approach
-	// to actually modify the embedded files in the Go binary
-
-	// Read the updated database
-	updatedDB, err := os.ReadFile(env.dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to read updated database: %v", err)
-	}
-
-	// Create a new binary with updated embedded files
-	// This would typically involve:
-	// 1. Reading the original binary
-	// 2. Locating the embedded file sections
-	// 3. Replacing the database content
-	// 4. Writing the new binary
-
-	// For now, we'll save the updated database alongside the binary
-	backupPath := env.originalBin + ".updated.db"
-	if err := os.WriteFile(backupPath, updatedDB, 0644); err != nil {
-		return fmt.Errorf("failed to write backup database: %v", err)
-	}
-
-	fmt.Printf("💾 Updated database saved to: %s\n", backupPath)
-	fmt.Println("📝 Note: Manual repackaging required for full binary update")
-
-	return nil
-}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// manifestEntryName is the payload entry the manifest itself is stored
+// under. It is always written in plaintext, even when every other entry is
+// encrypted, so a reader can learn whether a key is required before it
+// tries to decrypt anything.
+const manifestEntryName = "manifest.json"
+
+// ManifestEntry records the expected plaintext size and checksum of one
+// payload entry.
+type ManifestEntry struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest is the embedded integrity/encryption record for a payload,
+// modeled on Go's module checksum database and restic's repository
+// manifest: every entry's plaintext checksum is recorded up front, so
+// tampering with the stored bytes (or with an encrypted entry's contents)
+// is caught at extraction time rather than silently accepted.
+type Manifest struct {
+	Encrypted bool                     `json:"encrypted"`
+	Entries   map[string]ManifestEntry `json:"entries"`
+}
+
+func newManifest(encrypted bool) *Manifest {
+	return &Manifest{Encrypted: encrypted, Entries: map[string]ManifestEntry{}}
+}
+
+func (m *Manifest) record(name string, plaintext []byte) {
+	sum := sha256.Sum256(plaintext)
+	m.Entries[name] = ManifestEntry{SHA256: hex.EncodeToString(sum[:]), Size: int64(len(plaintext))}
+}
+
+// verify confirms data hashes to what the manifest recorded for name. It is
+// a no-op for a nil manifest or for names the manifest doesn't track, since
+// payloads built before this feature (or entries added after release)
+// simply have no manifest entry to check against.
+func (m *Manifest) verify(name string, data []byte) error {
+	if m == nil {
+		return nil
+	}
+	expected, ok := m.Entries[name]
+	if !ok {
+		return nil
+	}
+	if int64(len(data)) != expected.Size {
+		return fmt.Errorf("manifest size mismatch for %q: expected %d bytes, got %d", name, expected.Size, len(data))
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected.SHA256 {
+		return fmt.Errorf("manifest checksum mismatch for %q: payload entry may have been tampered with", name)
+	}
+	return nil
+}
+
+func marshalManifest(m *Manifest) ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	return data, nil
+}
+
+func parseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return &m, nil
+}
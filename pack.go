@@ -0,0 +1,549 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// packTarget is one {GOOS, GOARCH} pair the pack subcommand builds a
+// self-contained binary for.
+type packTarget struct {
+	GOOS   string
+	GOARCH string
+}
+
+var packTargets = []packTarget{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+	{"windows", "arm64"},
+}
+
+// nodeEntryName is the payload entry name extractNodeBinary looks for at
+// runtime, e.g. "node-linux-x64" or "node-windows-arm64.exe".
+func (t packTarget) nodeEntryName() string {
+	arch := "x64"
+	if t.GOARCH == "arm64" {
+		arch = "arm64"
+	}
+	name := fmt.Sprintf("node-%s-%s", t.GOOS, arch)
+	if t.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// nodeReleaseName is the tarball (or zip, on Windows) nodejs.org publishes
+// for this target under https://nodejs.org/dist/<version>/.
+func (t packTarget) nodeReleaseName(version string) string {
+	osName, arch, ext := t.GOOS, "x64", "tar.gz"
+	if t.GOARCH == "arm64" {
+		arch = "arm64"
+	}
+	if t.GOOS == "windows" {
+		osName, ext = "win", "zip"
+	}
+	return fmt.Sprintf("node-%s-%s-%s.%s", version, osName, arch, ext)
+}
+
+// packStubSource replaces this file in the scratch build directory:
+// main.go's argument dispatch references runPack by name, so the stub
+// build needs something to satisfy that without pulling in the actual
+// packing tool (and its downloader/tar-building code, which has no
+// business being baked into a shipped binary).
+const packStubSource = `package main
+
+import "fmt"
+
+func runPack(args []string) error {
+	return fmt.Errorf("pack is not available in a packed binary")
+}
+`
+
+// runtimeSourceFiles lists every *.go file that belongs in a shipped
+// binary: everything in dir except this file (pack.go) and any _test.go
+// files. Discovering them this way, rather than hand-maintaining a list,
+// means a newly added runtime file (a new sandbox_*.go or state_*.go, say)
+// is picked up automatically instead of silently missing from the stub
+// build until someone notices the undefined symbol.
+func runtimeSourceFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source directory %s: %v", dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".go" {
+			continue
+		}
+		if name == "pack.go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// runPack implements `kontained pack <project-dir> [--out dist/]`: it
+// prepares node_modules, the dev server, and the project database once,
+// then builds and appends a payload for every target triple.
+func runPack(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	outDir := fs.String("out", "dist", "output directory for built binaries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: kontained pack <project-dir> [--out dist/]")
+	}
+	projectDir := fs.Arg(0)
+	// Flags may also follow the project directory.
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 Packing project: %s\n", projectDir)
+
+	scratch, err := os.MkdirTemp("", "kontained-pack-*")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch dir: %v", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	fmt.Println("📥 Installing production node_modules...")
+	nodeModulesTar, err := packNodeModules(projectDir, scratch)
+	if err != nil {
+		return fmt.Errorf("failed to package node_modules: %v", err)
+	}
+
+	devServer, err := os.ReadFile(filepath.Join(projectDir, "dev-server.ts"))
+	if err != nil {
+		return fmt.Errorf("failed to read dev-server.ts: %v", err)
+	}
+
+	projectDB, err := loadOrCreateProjectDB(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to load project.db: %v", err)
+	}
+
+	// kontained.yaml is optional; a project with no triggers just omits it.
+	kontainedYAML, _ := os.ReadFile(filepath.Join(projectDir, "kontained.yaml"))
+
+	key, err := loadEncryptionKey()
+	if err != nil {
+		return fmt.Errorf("failed to load encryption key: %v", err)
+	}
+
+	cacheDir, err := nodeCacheDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %v", *outDir, err)
+	}
+
+	for _, target := range packTargets {
+		fmt.Printf("🔧 Building for %s/%s...\n", target.GOOS, target.GOARCH)
+
+		nodeBinary, err := fetchNodeBinary(cacheDir, target)
+		if err != nil {
+			return fmt.Errorf("failed to fetch Node.js for %s/%s: %v", target.GOOS, target.GOARCH, err)
+		}
+
+		entries := []namedData{
+			{Name: target.nodeEntryName(), Data: nodeBinary},
+			{Name: "dev-server.ts", Data: devServer},
+			{Name: "project.db", Data: projectDB},
+			{Name: "node_modules.tar.gz", Data: nodeModulesTar},
+		}
+		if len(kontainedYAML) > 0 {
+			entries = append(entries, namedData{Name: triggerConfigEntry, Data: kontainedYAML})
+		}
+
+		payload, err := buildManifestPayload(entries, key)
+		if err != nil {
+			return fmt.Errorf("failed to build payload for %s/%s: %v", target.GOOS, target.GOARCH, err)
+		}
+
+		binName := fmt.Sprintf("kontained-%s-%s", target.GOOS, target.GOARCH)
+		if target.GOOS == "windows" {
+			binName += ".exe"
+		}
+		outPath := filepath.Join(*outDir, binName)
+
+		if err := buildTargetBinary(target, outPath); err != nil {
+			return fmt.Errorf("failed to build %s/%s: %v", target.GOOS, target.GOARCH, err)
+		}
+
+		out, err := os.OpenFile(outPath, os.O_APPEND|os.O_WRONLY, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to open built binary %s: %v", outPath, err)
+		}
+		_, writeErr := out.Write(payload)
+		closeErr := out.Close()
+		if writeErr != nil {
+			return fmt.Errorf("failed to append payload to %s: %v", outPath, writeErr)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("failed to finalize %s: %v", outPath, closeErr)
+		}
+
+		fmt.Printf("✅ %s\n", outPath)
+	}
+
+	return nil
+}
+
+// buildTargetBinary copies the runtime-only source files into a scratch
+// directory and runs `go build` there with GOOS/GOARCH set, so the
+// resulting binary has none of the pack tooling compiled into it.
+func buildTargetBinary(target packTarget, outPath string) error {
+	selfDir, err := sourceDir()
+	if err != nil {
+		return err
+	}
+
+	stubDir, err := os.MkdirTemp("", "kontained-stub-*")
+	if err != nil {
+		return fmt.Errorf("failed to create stub build dir: %v", err)
+	}
+	defer os.RemoveAll(stubDir)
+
+	sourceFiles, err := runtimeSourceFiles(selfDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range sourceFiles {
+		data, err := os.ReadFile(filepath.Join(selfDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read runtime source %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(stubDir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write stub source %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(stubDir, "pack_stub.go"), []byte(packStubSource), 0644); err != nil {
+		return fmt.Errorf("failed to write pack stub: %v", err)
+	}
+
+	// go.mod/go.sum aren't runtime source files, but `go build` needs them
+	// present in stubDir (or any parent) to resolve the runtime's own
+	// imports (fsnotify, yaml.v3, the state backends' SDKs, ...).
+	for _, name := range []string{"go.mod", "go.sum"} {
+		data, err := os.ReadFile(filepath.Join(selfDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(stubDir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write stub %s: %v", name, err)
+		}
+	}
+
+	absOut, err := filepath.Abs(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %v", err)
+	}
+
+	cmd := exec.Command("go", "build", "-o", absOut, ".")
+	cmd.Dir = stubDir
+	cmd.Env = append(os.Environ(), "GOOS="+target.GOOS, "GOARCH="+target.GOARCH)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build failed: %v", err)
+	}
+	return nil
+}
+
+// sourceDir locates the directory this package's own source lives in, so
+// buildTargetBinary knows where to copy the runtime files from.
+func sourceDir() (string, error) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return "", fmt.Errorf("failed to determine source directory")
+	}
+	return filepath.Dir(file), nil
+}
+
+// packNodeModules runs `npm ci --production` for projectDir's package.json
+// in a scratch copy, then tars+gzips the result deterministically.
+func packNodeModules(projectDir, scratch string) ([]byte, error) {
+	installDir := filepath.Join(scratch, "npm-install")
+	if err := os.MkdirAll(installDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create npm install dir: %v", err)
+	}
+
+	for _, name := range []string{"package.json", "package-lock.json"} {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			if os.IsNotExist(err) && name == "package-lock.json" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(installDir, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+
+	cmd := exec.Command("npm", "ci", "--production")
+	cmd.Dir = installDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("npm ci failed: %v", err)
+	}
+
+	return tarGzDeterministic(filepath.Join(installDir, "node_modules"), "node_modules")
+}
+
+// tarGzDeterministic tars+gzips root (storing entries under prefix) with
+// sorted entries and zeroed mtimes/ownership, so packing the same
+// node_modules twice produces byte-identical output.
+func tarGzDeterministic(root, prefix string) ([]byte, error) {
+	var paths []string
+	if err := filepath.Walk(root, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", root, err)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %v", path, err)
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute relative path for %s: %v", path, err)
+		}
+		name := prefix
+		if rel != "." {
+			name = filepath.ToSlash(filepath.Join(prefix, rel))
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to build tar header for %s: %v", path, err)
+		}
+		header.Name = name
+		header.ModTime = time.Unix(0, 0)
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+
+		if info.IsDir() {
+			header.Name += "/"
+			if err := tw.WriteHeader(header); err != nil {
+				return nil, fmt.Errorf("failed to write tar header for %s: %v", path, err)
+			}
+			continue
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write tar header for %s: %v", path, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write tar entry for %s: %v", path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// loadOrCreateProjectDB reads projectDir/project.db, or returns an empty
+// database if the project doesn't have one yet; the dev server creates its
+// schema on first run either way.
+func loadOrCreateProjectDB(projectDir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, "project.db"))
+	if err == nil {
+		return data, nil
+	}
+	if os.IsNotExist(err) {
+		return []byte{}, nil
+	}
+	return nil, err
+}
+
+// nodeCacheDir returns (creating if needed) the local cache directory pack
+// downloads Node.js release archives into, so repeated `kontained pack`
+// runs don't re-download the same version.
+func nodeCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve cache directory: %v", err)
+	}
+	dir := filepath.Join(base, "kontained", "node")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create node cache dir %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+func nodeVersion() string {
+	if v := os.Getenv("KONTAINED_NODE_VERSION"); v != "" {
+		return v
+	}
+	return "v20.11.1"
+}
+
+const nodeDistBaseURL = "https://nodejs.org/dist"
+
+// fetchNodeBinary returns the `node` (or node.exe) executable for target,
+// downloading and sha256-verifying nodejs.org's release archive against its
+// published SHASUMS256.txt on first use, then caching it locally.
+func fetchNodeBinary(cacheDir string, target packTarget) ([]byte, error) {
+	version := nodeVersion()
+	archiveName := target.nodeReleaseName(version)
+	cachedPath := filepath.Join(cacheDir, archiveName)
+
+	if _, err := os.Stat(cachedPath); os.IsNotExist(err) {
+		if err := downloadVerifiedNodeArchive(version, archiveName, cachedPath); err != nil {
+			return nil, err
+		}
+	}
+
+	archive, err := os.ReadFile(cachedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached node archive %s: %v", cachedPath, err)
+	}
+
+	if target.GOOS == "windows" {
+		return extractNodeFromZip(archive)
+	}
+	return extractNodeFromTarGz(archive)
+}
+
+func downloadVerifiedNodeArchive(version, archiveName, destPath string) error {
+	baseURL := fmt.Sprintf("%s/%s", nodeDistBaseURL, version)
+
+	sums, err := httpGetBytes(fmt.Sprintf("%s/SHASUMS256.txt", baseURL))
+	if err != nil {
+		return fmt.Errorf("failed to fetch SHASUMS256.txt: %v", err)
+	}
+	expectedSum, err := findSHA256(sums, archiveName)
+	if err != nil {
+		return err
+	}
+
+	archive, err := httpGetBytes(fmt.Sprintf("%s/%s", baseURL, archiveName))
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", archiveName, err)
+	}
+
+	sum := sha256.Sum256(archive)
+	if hex.EncodeToString(sum[:]) != expectedSum {
+		return fmt.Errorf("checksum mismatch for %s: SHASUMS256.txt says %s", archiveName, expectedSum)
+	}
+
+	if err := os.WriteFile(destPath, archive, 0644); err != nil {
+		return fmt.Errorf("failed to cache %s: %v", archiveName, err)
+	}
+	return nil
+}
+
+func httpGetBytes(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func findSHA256(sums []byte, archiveName string) (string, error) {
+	for _, line := range strings.Split(string(sums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == archiveName {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s not listed in SHASUMS256.txt", archiveName)
+}
+
+func extractNodeFromTarGz(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open node archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == "node" {
+			return io.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("node executable not found in archive")
+}
+
+func extractNodeFromZip(archive []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open node archive: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) == "node.exe" {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read node.exe from archive: %v", err)
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+	}
+	return nil, fmt.Errorf("node.exe not found in archive")
+}
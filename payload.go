@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// payloadMagic ends the footer (it is the last six bytes of the file) so a
+// repacked binary can be told apart from a plain `go build` output that has nothing appended.
+const payloadMagic = "KNTND1"
+
+// footerLen is tableOffset(8) + totalLen(8) + magic(6), all relative to the
+// start of the appended container, never the whole file.
+const footerLen = 8 + 8 + len(payloadMagic)
+
+// PayloadEntry describes one file stored in the appended container: its
+// byte range relative to the start of the container, and a checksum taken
+// over those bytes so tampering or truncation is caught at extract time.
+type PayloadEntry struct {
+	Name   string
+	Offset int64
+	Length int64
+	SHA256 [32]byte
+}
+
+// Payload is a handle on the container appended to a Kontained binary. It
+// records the backing file's path and reopens it for each read, so entries
+// are read on demand instead of loading the whole binary into memory.
+type Payload struct {
+	binPath  string
+	start    int64 // offset of the container within binPath
+	entries  map[string]PayloadEntry
+	manifest *Manifest // nil if the container predates the manifest feature
+	key      []byte    // AES-256 key, set only when manifest.Encrypted
+}
+
+// OpenPayload locates and parses the container appended to binPath.
+func OpenPayload(binPath string) (*Payload, error) {
+	f, err := os.Open(binPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary %s: %v", binPath, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat binary: %v", err)
+	}
+	if info.Size() < int64(footerLen) {
+		return nil, fmt.Errorf("binary %s is too small to contain a payload", binPath)
+	}
+
+	footer := make([]byte, footerLen)
+	if _, err := f.ReadAt(footer, info.Size()-int64(footerLen)); err != nil {
+		return nil, fmt.Errorf("failed to read payload footer: %v", err)
+	}
+	if string(footer[16:]) != payloadMagic {
+		return nil, fmt.Errorf("binary %s has no appended payload (magic mismatch)", binPath)
+	}
+
+	tableOffset := int64(binary.LittleEndian.Uint64(footer[0:8]))
+	totalLen := int64(binary.LittleEndian.Uint64(footer[8:16]))
+	start := info.Size() - totalLen
+
+	entries, err := readEntryTable(f, start+tableOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry table: %v", err)
+	}
+
+	p := &Payload{binPath: binPath, start: start, entries: entries}
+
+	if _, ok := entries[manifestEntryName]; ok {
+		raw, err := p.rawReadEntry(manifestEntryName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %v", err)
+		}
+		manifest, err := parseManifest(raw)
+		if err != nil {
+			return nil, err
+		}
+		p.manifest = manifest
+
+		if manifest.Encrypted {
+			key, err := loadEncryptionKey()
+			if err != nil {
+				return nil, fmt.Errorf("payload is encrypted: %v", err)
+			}
+			if key == nil {
+				return nil, fmt.Errorf("payload is encrypted but neither %s nor %s is set", kontainedKeyEnv, kontainedKeyFileEnv)
+			}
+			p.key = key
+		}
+	}
+
+	return p, nil
+}
+
+// readEntryTable parses "count(uint32) then count * entries" starting at off.
+// Each entry is nameLen(uint16) name offset(uint64) length(uint64) sha256(32).
+func readEntryTable(f *os.File, off int64) (map[string]PayloadEntry, error) {
+	r := io.NewSectionReader(f, off, 1<<62)
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("failed to read entry count: %v", err)
+	}
+
+	entries := make(map[string]PayloadEntry, count)
+	for i := uint32(0); i < count; i++ {
+		var nameLen uint16
+		if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+			return nil, fmt.Errorf("failed to read entry %d name length: %v", i, err)
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, fmt.Errorf("failed to read entry %d name: %v", i, err)
+		}
+
+		var entryOffset, length uint64
+		if err := binary.Read(r, binary.LittleEndian, &entryOffset); err != nil {
+			return nil, fmt.Errorf("failed to read entry %d offset: %v", i, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, fmt.Errorf("failed to read entry %d length: %v", i, err)
+		}
+		var sum [32]byte
+		if _, err := io.ReadFull(r, sum[:]); err != nil {
+			return nil, fmt.Errorf("failed to read entry %d checksum: %v", i, err)
+		}
+
+		entries[string(name)] = PayloadEntry{
+			Name:   string(name),
+			Offset: int64(entryOffset),
+			Length: int64(length),
+			SHA256: sum,
+		}
+	}
+
+	return entries, nil
+}
+
+// entryNames returns the names of every entry in the container. Order is
+// not significant to readers, but callers that rebuild a payload use it to
+// decide what to carry over.
+func (p *Payload) entryNames() []string {
+	names := make([]string, 0, len(p.entries))
+	for name := range p.entries {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Has reports whether the container has an entry with the given name.
+func (p *Payload) Has(name string) bool {
+	_, ok := p.entries[name]
+	return ok
+}
+
+// ReadEntry returns the plaintext bytes for name: it verifies the entry
+// table checksum over the stored bytes, decrypts them if the payload's
+// manifest marks it encrypted, then verifies the manifest's checksum (and
+// size) of the result too. Either check failing means a tampered or
+// truncated payload, and both fail fast with a clear error.
+func (p *Payload) ReadEntry(name string) ([]byte, error) {
+	raw, err := p.rawReadEntry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.manifest != nil && p.manifest.Encrypted && name != manifestEntryName {
+		plaintext, err := decryptEntry(p.key, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt entry %q: %v", name, err)
+		}
+		if err := p.manifest.verify(name, plaintext); err != nil {
+			return nil, err
+		}
+		return plaintext, nil
+	}
+
+	if err := p.manifest.verify(name, raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// rawReadEntry returns the bytes stored for name exactly as they sit in the
+// container (ciphertext, if the payload is encrypted), verifying the
+// checksum recorded in the entry table so a truncated or tampered payload
+// fails fast.
+func (p *Payload) rawReadEntry(name string) ([]byte, error) {
+	entry, ok := p.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("payload entry %q not found", name)
+	}
+
+	f, err := os.Open(p.binPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary %s: %v", p.binPath, err)
+	}
+	defer f.Close()
+
+	data := make([]byte, entry.Length)
+	if _, err := f.ReadAt(data, p.start+entry.Offset); err != nil {
+		return nil, fmt.Errorf("failed to read payload entry %q: %v", name, err)
+	}
+
+	if sum := sha256.Sum256(data); sum != entry.SHA256 {
+		return nil, fmt.Errorf("payload entry %q failed checksum verification", name)
+	}
+
+	return data, nil
+}
+
+// namedData pairs an entry name with the bytes that will be written for it;
+// buildPayload and buildManifestPayload both take a slice of these.
+type namedData struct {
+	Name string
+	Data []byte
+}
+
+// buildPayload serializes entries (in the given order) into a single
+// container: the raw entry bytes followed by the entry table and footer.
+// The returned bytes are what repackBinary appends to the new executable.
+func buildPayload(entries []namedData) []byte {
+	var body []byte
+	table := make([]PayloadEntry, 0, len(entries))
+
+	offset := int64(0)
+	for _, e := range entries {
+		sum := sha256.Sum256(e.Data)
+		table = append(table, PayloadEntry{
+			Name:   e.Name,
+			Offset: offset,
+			Length: int64(len(e.Data)),
+			SHA256: sum,
+		})
+		body = append(body, e.Data...)
+		offset += int64(len(e.Data))
+	}
+
+	tableOffset := offset
+	tableBytes := encodeEntryTable(table)
+	body = append(body, tableBytes...)
+
+	totalLen := int64(len(body)) + int64(footerLen)
+	footer := make([]byte, 0, footerLen)
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(tableOffset))
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(totalLen))
+	footer = append(footer, payloadMagic...)
+
+	return append(body, footer...)
+}
+
+// buildManifestPayload builds a container the same way buildPayload does,
+// but first records each entry's plaintext checksum and size into an
+// embedded manifest.json, and, if key is non-nil, encrypts every entry
+// (other than the manifest itself) with AES-GCM using a fresh nonce.
+func buildManifestPayload(entries []namedData, key []byte) ([]byte, error) {
+	manifest := newManifest(key != nil)
+	stored := make([]namedData, 0, len(entries)+1)
+
+	for _, e := range entries {
+		manifest.record(e.Name, e.Data)
+
+		data := e.Data
+		if key != nil {
+			ciphertext, err := encryptEntry(key, e.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt entry %q: %v", e.Name, err)
+			}
+			data = ciphertext
+		}
+		stored = append(stored, namedData{Name: e.Name, Data: data})
+	}
+
+	manifestBytes, err := marshalManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	stored = append(stored, namedData{Name: manifestEntryName, Data: manifestBytes})
+
+	return buildPayload(stored), nil
+}
+
+func encodeEntryTable(entries []PayloadEntry) []byte {
+	var out []byte
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(entries)))
+	for _, e := range entries {
+		out = binary.LittleEndian.AppendUint16(out, uint16(len(e.Name)))
+		out = append(out, e.Name...)
+		out = binary.LittleEndian.AppendUint64(out, uint64(e.Offset))
+		out = binary.LittleEndian.AppendUint64(out, uint64(e.Length))
+		out = append(out, e.SHA256[:]...)
+	}
+	return out
+}
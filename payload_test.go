@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestBinary writes an arbitrary "executable" prefix followed by a
+// built payload container, the same shape repackBinary produces, and
+// returns its path.
+func writeTestBinary(t *testing.T, payload []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-binary")
+	data := append([]byte("#!/bin/sh\necho fake binary\n"), payload...)
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatalf("failed to write test binary: %v", err)
+	}
+	return path
+}
+
+func TestPayloadRoundTrip(t *testing.T) {
+	entries := []namedData{
+		{Name: "hello.txt", Data: []byte("hello, world")},
+		{Name: "project.db", Data: []byte{0x00, 0x01, 0x02, 0x03}},
+		{Name: "empty.txt", Data: []byte{}},
+	}
+
+	payload, err := buildManifestPayload(entries, nil)
+	if err != nil {
+		t.Fatalf("buildManifestPayload failed: %v", err)
+	}
+
+	p, err := OpenPayload(writeTestBinary(t, payload))
+	if err != nil {
+		t.Fatalf("OpenPayload failed: %v", err)
+	}
+
+	for _, e := range entries {
+		if !p.Has(e.Name) {
+			t.Errorf("expected payload to have entry %q", e.Name)
+		}
+		got, err := p.ReadEntry(e.Name)
+		if err != nil {
+			t.Errorf("ReadEntry(%q) failed: %v", e.Name, err)
+			continue
+		}
+		if string(got) != string(e.Data) {
+			t.Errorf("ReadEntry(%q) = %q, want %q", e.Name, got, e.Data)
+		}
+	}
+
+	if p.Has("does-not-exist") {
+		t.Error("expected missing entry to report Has() == false")
+	}
+	if _, err := p.ReadEntry("does-not-exist"); err == nil {
+		t.Error("expected ReadEntry of missing entry to fail")
+	}
+}
+
+func TestPayloadRoundTripEncrypted(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	entries := []namedData{
+		{Name: "project.db", Data: []byte("secret contents")},
+	}
+
+	payload, err := buildManifestPayload(entries, key)
+	if err != nil {
+		t.Fatalf("buildManifestPayload failed: %v", err)
+	}
+
+	binPath := writeTestBinary(t, payload)
+	t.Setenv(kontainedKeyEnv, hex.EncodeToString(key))
+
+	p, err := OpenPayload(binPath)
+	if err != nil {
+		t.Fatalf("OpenPayload failed: %v", err)
+	}
+
+	got, err := p.ReadEntry("project.db")
+	if err != nil {
+		t.Fatalf("ReadEntry failed: %v", err)
+	}
+	if string(got) != "secret contents" {
+		t.Errorf("ReadEntry = %q, want %q", got, "secret contents")
+	}
+}
+
+func TestOpenPayloadRejectsBinaryWithoutPayload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain-binary")
+	if err := os.WriteFile(path, []byte("just a regular executable"), 0755); err != nil {
+		t.Fatalf("failed to write test binary: %v", err)
+	}
+
+	if _, err := OpenPayload(path); err == nil {
+		t.Error("expected OpenPayload to fail on a binary with no appended container")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// repackBinary rewrites the running executable in place: it copies the
+// original binary up to the start of the appended payload into a temp
+// file, appends a payload with the updated database (other entries carried
+// over unchanged), then swaps it over originalBin. This is what lets
+// Shutdown persist dbModified changes without a `go build` round trip.
+func (env *DevEnvironment) repackBinary() error {
+	updatedDB, err := os.ReadFile(env.dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to read updated database: %v", err)
+	}
+
+	newPayload, err := env.rebuildPayload(updatedDB)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild payload: %v", err)
+	}
+
+	tmpPath := env.originalBin + ".new"
+	if err := writeRepackedBinary(env.originalBin, env.payload.start, newPayload, tmpPath); err != nil {
+		return fmt.Errorf("failed to write repacked binary: %v", err)
+	}
+
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to make repacked binary executable: %v", err)
+	}
+
+	if err := swapInRepackedBinary(env.originalBin, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to swap in repacked binary: %v", err)
+	}
+
+	return nil
+}
+
+// rebuildPayload re-serializes the container, replacing the project.db
+// entry with newDB and carrying every other entry over (re-decrypting and
+// re-encrypting as needed so the manifest and ciphertext stay consistent).
+func (env *DevEnvironment) rebuildPayload(newDB []byte) ([]byte, error) {
+	var key []byte
+	if env.payload.manifest != nil && env.payload.manifest.Encrypted {
+		k, err := loadEncryptionKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load encryption key for repack: %v", err)
+		}
+		key = k
+	}
+
+	names := env.payload.entryNames()
+	entries := make([]namedData, 0, len(names))
+
+	for _, name := range names {
+		if name == manifestEntryName {
+			continue
+		}
+		if name == "project.db" {
+			entries = append(entries, namedData{Name: name, Data: newDB})
+			continue
+		}
+
+		data, err := env.payload.ReadEntry(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing entry %q: %v", name, err)
+		}
+		entries = append(entries, namedData{Name: name, Data: data})
+	}
+
+	return buildManifestPayload(entries, key)
+}
+
+// writeRepackedBinary copies the first payloadStart bytes of originalBin
+// (i.e. everything except the old appended payload) into outPath, then
+// appends newPayload.
+func writeRepackedBinary(originalBin string, payloadStart int64, newPayload []byte, outPath string) error {
+	src, err := os.Open(originalBin)
+	if err != nil {
+		return fmt.Errorf("failed to open original binary: %v", err)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output binary: %v", err)
+	}
+	defer out.Close()
+
+	if _, err := io.CopyN(out, src, payloadStart); err != nil {
+		return fmt.Errorf("failed to copy executable code: %v", err)
+	}
+
+	if _, err := out.Write(newPayload); err != nil {
+		return fmt.Errorf("failed to append new payload: %v", err)
+	}
+
+	return out.Close()
+}
+
+// swapInRepackedBinary atomically replaces originalBin with tmpPath. On
+// Windows the running executable can't be unlinked while the process holds
+// it open, so we rename it out of the way first and move the new binary
+// into place; a future launch can clean up the `.old` file.
+func swapInRepackedBinary(originalBin, tmpPath string) error {
+	if runtime.GOOS == "windows" {
+		oldPath := originalBin + ".old"
+		os.Remove(oldPath)
+		if err := os.Rename(originalBin, oldPath); err != nil {
+			return fmt.Errorf("failed to move running executable aside: %v", err)
+		}
+		if err := os.Rename(tmpPath, originalBin); err != nil {
+			// Best effort: restore the previous binary so the install isn't left broken.
+			os.Rename(oldPath, originalBin)
+			return fmt.Errorf("failed to move repacked binary into place: %v", err)
+		}
+		return nil
+	}
+
+	return os.Rename(tmpPath, originalBin)
+}
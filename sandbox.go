@@ -0,0 +1,36 @@
+package main
+
+import "os"
+
+// sandboxEnvVar opts a run into the namespace/seccomp sandbox without
+// needing a command-line flag, for use from process supervisors.
+const sandboxEnvVar = "KONTAINED_SANDBOX"
+
+// sandboxEnabled reports whether the Node dev server should be started
+// inside the sandbox, via --sandbox on the command line or KONTAINED_SANDBOX=1.
+func sandboxEnabled() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--sandbox" {
+			return true
+		}
+	}
+	return os.Getenv(sandboxEnvVar) == "1"
+}
+
+// sandboxNetworkEnvVar opts the sandbox into its own network namespace
+// (CLONE_NEWNET), isolating it from the host's loopback interface. This
+// defaults to off: the dev server's whole point is serving
+// http://localhost:3000 to the host, and a fresh network namespace with no
+// veth pair or userland proxy set up makes that unreachable. Isolating
+// networking too means accepting that tradeoff, so it's opt-in via
+// --sandbox-network or KONTAINED_SANDBOX_NETWORK=1.
+const sandboxNetworkEnvVar = "KONTAINED_SANDBOX_NETWORK"
+
+func sandboxNetworkIsolationEnabled() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--sandbox-network" {
+			return true
+		}
+	}
+	return os.Getenv(sandboxNetworkEnvVar) == "1"
+}
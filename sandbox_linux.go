@@ -0,0 +1,178 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const prSetNoNewPrivs = 38
+
+// applySandbox isolates the Node child the way the pkgsite-metrics analysis
+// worker and podman/containers isolate untrusted binaries: fresh mount,
+// pid, ipc, uts, and user namespaces, with the invoking user mapped back to
+// uid/gid 0 inside them (CLONE_NEWUSER is what lets an unprivileged caller
+// create the other namespaces at all). CLONE_NEWNET is opt-in — see
+// sandboxNetworkIsolationEnabled — because it isolates the loopback
+// interface along with everything else, and the dev server's whole point
+// is serving http://localhost:3000 to the host.
+//
+// The namespaces only take effect for the child process, and pivot_root,
+// mounting /proc, dropping capabilities, and loading the seccomp filter all
+// have to happen *inside* it before node starts — so instead of execing
+// node directly, we re-exec this same binary with an internal
+// "--sandbox-init" marker that does that setup and then execs into node.
+func applySandbox(cmd *exec.Cmd, env *DevEnvironment) error {
+	fmt.Println("🔒 Sandboxing dev server (Linux namespaces + seccomp)")
+
+	cloneflags := syscall.CLONE_NEWNS |
+		syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC |
+		syscall.CLONE_NEWUTS |
+		syscall.CLONE_NEWUSER
+
+	if sandboxNetworkIsolationEnabled() {
+		fmt.Println("🔒 Isolating sandbox networking too (localhost:3000 will not be reachable from the host)")
+		cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  uintptr(cloneflags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+
+	cmd.Path = env.originalBin
+	cmd.Args = []string{
+		env.originalBin, "--sandbox-init",
+		env.tempDir,
+		filepath.Base(env.nodeExePath),
+		filepath.Base(env.serverPath),
+		filepath.Base(env.dbPath),
+		"3000",
+	}
+
+	// cmd.Env was already set by the caller to devServerEnv(), which points
+	// NODE_PATH and PATH at host-absolute paths under env.tempDir. Once
+	// sandboxInit pivot_roots into env.tempDir, that directory is "/", so
+	// those paths no longer resolve — recompute them relative to the new
+	// root instead of carrying the host-absolute versions across the exec.
+	cmd.Env = sandboxedEnv(cmd.Env)
+
+	return nil
+}
+
+// sandboxedEnv replaces NODE_PATH and PATH (as set by devServerEnv, pointed
+// at env.tempDir on the host) with their equivalents under the sandbox's
+// new root, "/", once pivot_root has made env.tempDir the filesystem root.
+func sandboxedEnv(hostEnv []string) []string {
+	out := make([]string, 0, len(hostEnv))
+	for _, kv := range hostEnv {
+		if strings.HasPrefix(kv, "NODE_PATH=") || strings.HasPrefix(kv, "PATH=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return append(out,
+		"NODE_PATH=/node_modules",
+		fmt.Sprintf("PATH=/%c%s", os.PathListSeparator, os.Getenv("PATH")),
+	)
+}
+
+// sandboxInit runs as PID 1 of the new namespaces, before node ever starts.
+// It never returns on success: the last step execs into node, replacing
+// this process image entirely.
+func sandboxInit(args []string) {
+	if len(args) < 5 {
+		fmt.Fprintln(os.Stderr, "sandbox-init: expected <tempDir> <node> <server.ts> <db> <port>")
+		return
+	}
+	tempDir, nodeName, serverName, dbName, port := args[0], args[1], args[2], args[3], args[4]
+
+	if err := pivotRootInto(tempDir); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox-init: pivot_root failed: %v\n", err)
+		return
+	}
+	if err := mountFreshProc(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox-init: mounting /proc failed: %v\n", err)
+		return
+	}
+	if err := dropAllCapabilities(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox-init: dropping capabilities failed: %v\n", err)
+		return
+	}
+	if err := installSeccompFilter(); err != nil {
+		// Seccomp is a defense-in-depth extra, not load-bearing the way the
+		// namespaces and capability drop are, so we warn and keep going.
+		fmt.Fprintf(os.Stderr, "sandbox-init: seccomp filter unavailable, continuing without it: %v\n", err)
+	} else {
+		// installSeccompFilter only denies a handful of named syscalls
+		// (ptrace, mount, reboot, bpf, kexec); it is not the allowlist of
+		// syscalls Node actually needs. Surface that at runtime, not just in
+		// a code comment, since --sandbox's whole premise is running an
+		// untrusted payload and a denylist is a materially weaker guarantee.
+		fmt.Println("⚠️  Seccomp filter is a syscall denylist, not a Node-specific allowlist — it is a partial guarantee, not full untrusted-code isolation")
+	}
+
+	nodePath := "/" + nodeName
+	argv := []string{nodePath, "/" + serverName, "/" + dbName, port}
+	if err := syscall.Exec(nodePath, argv, os.Environ()); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox-init: exec node failed: %v\n", err)
+	}
+}
+
+// pivotRootInto makes newRoot the process's root filesystem, leaving the
+// rest of the host filesystem unreachable. newRoot must first be made a
+// mount point (pivot_root requires it), then the old root is unmounted and
+// removed once we've chdir'd away from it.
+func pivotRootInto(newRoot string) error {
+	if err := syscall.Mount(newRoot, newRoot, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind mount %s: %v", newRoot, err)
+	}
+
+	oldRoot := filepath.Join(newRoot, ".kontained-old-root")
+	if err := os.MkdirAll(oldRoot, 0700); err != nil {
+		return fmt.Errorf("create old-root dir: %v", err)
+	}
+
+	if err := syscall.PivotRoot(newRoot, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %v", err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return fmt.Errorf("chdir to new root: %v", err)
+	}
+
+	putOld := "/.kontained-old-root"
+	if err := syscall.Unmount(putOld, syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount old root: %v", err)
+	}
+	os.Remove(putOld)
+
+	return nil
+}
+
+// mountFreshProc mounts a new procfs so /proc inside the sandbox reflects
+// only the new pid namespace, not the host's process table.
+func mountFreshProc() error {
+	if err := os.MkdirAll("/proc", 0555); err != nil {
+		return fmt.Errorf("create /proc: %v", err)
+	}
+	return syscall.Mount("proc", "/proc", "proc", 0, "")
+}
+
+// dropAllCapabilities removes every capability the process holds and sets
+// no_new_privs so it can never regain them (e.g. via a setuid binary).
+// Entering a fresh user namespace grants the creating process a full
+// capability set *within that namespace*, so this has to run before node
+// starts, not be inherited from the parent.
+func dropAllCapabilities() error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("prctl(PR_SET_NO_NEW_PRIVS): %v", errno)
+	}
+	return archDropCapabilitySets()
+}
@@ -0,0 +1,106 @@
+//go:build linux && amd64
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// sysCapset and the _LINUX_CAPABILITY_VERSION_3 header let us zero out the
+// process's effective/permitted/inheritable capability sets directly,
+// rather than dropping capabilities one at a time.
+const (
+	sysCapset   = 126
+	capVersion3 = 0x20080522
+)
+
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+func archDropCapabilitySets() error {
+	header := capHeader{version: capVersion3, pid: 0}
+	data := [2]capData{} // zeroed: every capability bit cleared
+
+	if _, _, errno := syscall.Syscall(sysCapset, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("capset: %v", errno)
+	}
+	return nil
+}
+
+// Classic BPF opcodes/fields used to build the seccomp program below. Named
+// individually (rather than pulled from golang.org/x/sys/unix) to avoid
+// adding a dependency just for a handful of constants.
+const (
+	bpfLdWAbs = 0x00 | 0x00 | 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeqK   = 0x05 | 0x10 | 0x00 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfRetK   = 0x06 | 0x00        // BPF_RET | BPF_K
+
+	seccompRetAllow = 0x7fff0000
+	seccompRetErrno = 0x00050000
+	eperm           = 1
+
+	prSetSeccomp      = 22
+	seccompModeFilter = 2
+)
+
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+// deniedSyscalls are linux/amd64 syscall numbers for operations a sandboxed
+// dev server has no business making: ptrace (debugger/code injection),
+// mount/umount2 (escaping the pivot_root), reboot, bpf (loading more eBPF
+// programs), and kexec (replacing the running kernel).
+//
+// This is a denylist rather than the full Node syscall allowlist the
+// sandbox ultimately wants: without a way to exercise Node under the filter
+// here, an allowlist risks silently breaking the dev server on a missed
+// syscall. The denylist is the safer first cut; tightening it to an
+// allowlist is tracked as follow-up work once this has real mileage.
+var deniedSyscalls = []uint32{
+	101, // ptrace
+	165, // mount
+	166, // umount2
+	169, // reboot
+	321, // bpf
+	246, // kexec_load
+	320, // kexec_file_load
+}
+
+func installSeccompFilter() error {
+	prog := make([]sockFilter, 0, 2+2*len(deniedSyscalls))
+	prog = append(prog, sockFilter{code: bpfLdWAbs, k: 0}) // load syscall number (seccomp_data.nr)
+
+	for _, nr := range deniedSyscalls {
+		prog = append(prog,
+			sockFilter{code: bpfJeqK, jt: 0, jf: 1, k: nr},
+			sockFilter{code: bpfRetK, k: seccompRetErrno | eperm},
+		)
+	}
+	prog = append(prog, sockFilter{code: bpfRetK, k: seccompRetAllow})
+
+	fprog := sockFprog{len: uint16(len(prog)), filter: &prog[0]}
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&fprog))); errno != 0 {
+		return fmt.Errorf("prctl(PR_SET_SECCOMP): %v", errno)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build linux && !amd64
+
+package main
+
+import "fmt"
+
+// The raw capset/seccomp syscall numbers and BPF encoding in
+// sandbox_linux_amd64.go are amd64-specific; other Linux architectures
+// fall back to reporting the sandbox as unavailable rather than guessing.
+func archDropCapabilitySets() error {
+	return fmt.Errorf("capability dropping is only implemented for linux/amd64")
+}
+
+func installSeccompFilter() error {
+	return fmt.Errorf("seccomp filtering is only implemented for linux/amd64")
+}
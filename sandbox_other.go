@@ -0,0 +1,23 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// applySandbox has no namespace/seccomp equivalent wired up for darwin or
+// windows yet, so --sandbox degrades to the normal unsandboxed behavior
+// with a clear warning rather than silently doing nothing.
+func applySandbox(cmd *exec.Cmd, env *DevEnvironment) error {
+	fmt.Printf("⚠️  --sandbox is not supported on %s; running without isolation\n", runtime.GOOS)
+	return nil
+}
+
+// sandboxInit is only reachable via the internal --sandbox-init re-exec,
+// which applySandbox never triggers on this platform.
+func sandboxInit(args []string) {
+	fmt.Println("sandbox-init is only supported on Linux")
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// sendSignal delivers the named POSIX signal to proc, used by triggers to
+// tell a supervised dev server to reload before its replacement is started.
+func sendSignal(proc *os.Process, name string) error {
+	sig, ok := namedSignals[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("unsupported signal %q", name)
+	}
+	return proc.Signal(sig)
+}
+
+var namedSignals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// sendSignal on Windows maps SIGKILL and SIGTERM to proc.Kill (TerminateProcess);
+// anything else is reported as unsupported rather than silently ignored.
+func sendSignal(proc *os.Process, name string) error {
+	switch strings.ToUpper(name) {
+	case "SIGKILL", "SIGTERM":
+		return proc.Kill()
+	default:
+		return fmt.Errorf("signal %q is not supported on windows", name)
+	}
+}
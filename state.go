@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// StateBackend lets the mutable project database live somewhere other than
+// the binary's own appended payload. Load fetches the latest bytes at
+// startup; Save persists a change. prevHash lets the backend detect a
+// conflicting concurrent write, the same optimistic-concurrency trick the
+// pkgsite analysis worker uses tagging GCS objects by content hash.
+type StateBackend interface {
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte, prevHash string) error
+}
+
+// ErrStateNotFound is returned by Load when the backend has nothing stored
+// yet, so callers can fall back to the embedded database instead of
+// failing startup.
+var ErrStateNotFound = errors.New("state: no data found")
+
+// ErrStateConflict is returned by Save when prevHash no longer matches
+// what's stored, meaning something else wrote a newer version first.
+var ErrStateConflict = errors.New("state: conflicting concurrent write")
+
+// stateURLEnvVar names the remote state backend to use, e.g.
+// "file:///var/lib/app/project.db", "s3://bucket/key", "gs://bucket/object",
+// or "https://host/path". --state on the command line takes precedence.
+const stateURLEnvVar = "KONTAINED_STATE_URL"
+
+func stateURLFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--state" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// newStateBackend resolves the configured backend, returning (nil, nil) if
+// neither --state nor KONTAINED_STATE_URL is set, meaning state stays
+// purely in the binary's own payload.
+func newStateBackend() (StateBackend, error) {
+	rawURL := stateURLFromArgs(os.Args[1:])
+	if rawURL == "" {
+		rawURL = os.Getenv(stateURLEnvVar)
+	}
+	if rawURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state URL %q: %v", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return newFileBackend(u), nil
+	case "s3":
+		return newS3Backend(u), nil
+	case "gs":
+		return newGCSBackend(u), nil
+	case "http", "https":
+		return newHTTPBackend(u), nil
+	default:
+		return nil, fmt.Errorf("unsupported state backend scheme %q", u.Scheme)
+	}
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
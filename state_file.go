@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// fileBackend stores the database at a plain path, e.g. for a shared NFS
+// mount or just a stable location outside the binary's temp workspace.
+type fileBackend struct {
+	path string
+}
+
+func newFileBackend(u *url.URL) *fileBackend {
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	return &fileBackend{path: path}
+}
+
+func (b *fileBackend) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return nil, ErrStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", b.path, err)
+	}
+	return data, nil
+}
+
+func (b *fileBackend) Save(ctx context.Context, data []byte, prevHash string) error {
+	if prevHash != "" {
+		current, err := b.Load(ctx)
+		switch {
+		case err == nil:
+			if hashHex(current) != prevHash {
+				return ErrStateConflict
+			}
+		case err == ErrStateNotFound:
+			// Nothing to conflict with yet.
+		default:
+			return err
+		}
+	}
+
+	if err := os.WriteFile(b.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", b.path, err)
+	}
+	return nil
+}
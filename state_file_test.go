@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFileBackend(t *testing.T) *fileBackend {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "project.db")
+	u, err := url.Parse("file://" + path)
+	if err != nil {
+		t.Fatalf("failed to parse test file URL: %v", err)
+	}
+	return newFileBackend(u)
+}
+
+func TestFileBackendLoadNotFound(t *testing.T) {
+	b := newTestFileBackend(t)
+	if _, err := b.Load(context.Background()); err != ErrStateNotFound {
+		t.Fatalf("expected ErrStateNotFound, got %v", err)
+	}
+}
+
+func TestFileBackendSaveAndLoadRoundTrip(t *testing.T) {
+	b := newTestFileBackend(t)
+	ctx := context.Background()
+
+	if err := b.Save(ctx, []byte("v1"), ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := b.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(data) != "v1" {
+		t.Errorf("Load = %q, want %q", data, "v1")
+	}
+}
+
+func TestFileBackendSaveDetectsConflict(t *testing.T) {
+	b := newTestFileBackend(t)
+	ctx := context.Background()
+
+	if err := b.Save(ctx, []byte("v1"), ""); err != nil {
+		t.Fatalf("initial Save failed: %v", err)
+	}
+
+	// Someone else writes v2 without us knowing.
+	if err := os.WriteFile(b.path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("failed to simulate concurrent write: %v", err)
+	}
+
+	// We still think the last version was v1, so our write should conflict.
+	if err := b.Save(ctx, []byte("v3"), hashHex([]byte("v1"))); err != ErrStateConflict {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+
+	// The conflicting write must not have been applied.
+	data, err := b.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(data) != "v2" {
+		t.Errorf("Load = %q, want %q (conflicting write should not land)", data, "v2")
+	}
+}
+
+func TestFileBackendSaveWithCorrectPrevHashSucceeds(t *testing.T) {
+	b := newTestFileBackend(t)
+	ctx := context.Background()
+
+	if err := b.Save(ctx, []byte("v1"), ""); err != nil {
+		t.Fatalf("initial Save failed: %v", err)
+	}
+
+	if err := b.Save(ctx, []byte("v2"), hashHex([]byte("v1"))); err != nil {
+		t.Fatalf("Save with correct prevHash failed: %v", err)
+	}
+
+	data, err := b.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(data) != "v2" {
+		t.Errorf("Load = %q, want %q", data, "v2")
+	}
+}
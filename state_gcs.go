@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsBackend stores the database as a single GCS object, recording its
+// content hash in object metadata (the "sha-tagged objects" pattern the
+// pkgsite analysis worker uses) so Save can detect a concurrent write
+// before overwriting it.
+type gcsBackend struct {
+	bucket string
+	object string
+}
+
+func newGCSBackend(u *url.URL) *gcsBackend {
+	return &gcsBackend{bucket: u.Host, object: strings.TrimPrefix(u.Path, "/")}
+}
+
+func (b *gcsBackend) handle(ctx context.Context) (*storage.ObjectHandle, *storage.Client, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create GCS client: %v", err)
+	}
+	return client.Bucket(b.bucket).Object(b.object), client, nil
+}
+
+func (b *gcsBackend) Load(ctx context.Context) ([]byte, error) {
+	obj, client, err := b.handle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	r, err := obj.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gs://%s/%s: %v", b.bucket, b.object, err)
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func (b *gcsBackend) Save(ctx context.Context, data []byte, prevHash string) error {
+	obj, client, err := b.handle(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if prevHash != "" {
+		attrs, err := obj.Attrs(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to read gs://%s/%s attributes: %v", b.bucket, b.object, err)
+		}
+		if attrs != nil && attrs.Metadata["sha256"] != prevHash {
+			return ErrStateConflict
+		}
+	}
+
+	w := obj.NewWriter(ctx)
+	w.Metadata = map[string]string{"sha256": hashHex(data)}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write gs://%s/%s: %v", b.bucket, b.object, err)
+	}
+	return w.Close()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// httpBackend stores the database at an arbitrary HTTP(S) endpoint: GET to
+// fetch, PUT to save, sending the previous content's sha256 as If-Match for
+// optimistic concurrency. (The S3 and GCS backends instead compare prevHash
+// against a sha256 kept in object metadata.)
+type httpBackend struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPBackend(u *url.URL) *httpBackend {
+	return &httpBackend{url: u.String(), client: http.DefaultClient}
+}
+
+func (b *httpBackend) Load(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %v", b.url, err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GET %s: %v", b.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrStateNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s fetching %s", resp.Status, b.url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (b *httpBackend) Save(ctx context.Context, data []byte, prevHash string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build request for %s: %v", b.url, err)
+	}
+	if prevHash != "" {
+		req.Header.Set("If-Match", prevHash)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to PUT %s: %v", b.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrStateConflict
+	default:
+		return fmt.Errorf("unexpected status %s writing %s", resp.Status, b.url)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Backend stores the database as a single S3 object, recording its
+// content hash in object metadata the same way gcsBackend does, so Save
+// can detect a concurrent write before overwriting it. S3's own ETag for a
+// non-multipart object is a quoted MD5 digest, not comparable to the
+// sha256 hex digest prevHash carries, so conflict detection is done
+// ourselves against the stored metadata rather than via If-Match.
+type s3Backend struct {
+	bucket string
+	key    string
+}
+
+func newS3Backend(u *url.URL) *s3Backend {
+	return &s3Backend{bucket: u.Host, key: strings.TrimPrefix(u.Path, "/")}
+}
+
+func (b *s3Backend) client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+func (b *s3Backend) Load(ctx context.Context) ([]byte, error) {
+	client, err := b.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrStateNotFound
+		}
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %v", b.bucket, b.key, err)
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
+func (b *s3Backend) Save(ctx context.Context, data []byte, prevHash string) error {
+	client, err := b.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if prevHash != "" {
+		head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(b.bucket),
+			Key:    aws.String(b.key),
+		})
+		if err != nil {
+			var notFound *types.NotFound
+			if !errors.As(err, &notFound) {
+				return fmt.Errorf("failed to head s3://%s/%s: %v", b.bucket, b.key, err)
+			}
+		} else if head.Metadata["sha256"] != prevHash {
+			return ErrStateConflict
+		}
+	}
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(b.key),
+		Body:     bytes.NewReader(data),
+		Metadata: map[string]string{"sha256": hashHex(data)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %v", b.bucket, b.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// shellCommand wraps cmdLine in the platform's shell so trigger commands can
+// use pipes, globs, and the rest of normal shell syntax rather than being
+// split into argv ourselves.
+func shellCommand(ctx context.Context, cmdLine string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", cmdLine)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", cmdLine)
+}
+
+// debounceWindow coalesces bursts of filesystem events (editors commonly
+// emit several WRITE/CHMOD events per save) into a single reaction.
+const debounceWindow = 300 * time.Millisecond
+
+// superviseDevServer watches env.tempDir with fsnotify and reacts to real
+// WRITE/CREATE events instead of the old 2-second polling loop: a
+// project.db write is hashed to decide whether dbModified should flip, and
+// any kontained.yaml trigger whose patterns match a changed file is run.
+func (env *DevEnvironment) superviseDevServer() error {
+	env.lastDBHash = env.getDatabaseHash()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create filesystem watcher: %v", err)
+	}
+
+	if err := addRecursiveWatch(watcher, env.tempDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %v", env.tempDir, err)
+	}
+
+	go env.watchLoop(watcher)
+	return nil
+}
+
+// addRecursiveWatch registers a watch on root and every directory beneath
+// it. A single directory's watcher.Add failing (most commonly a deeply
+// nested node_modules pushing past fs.inotify.max_user_watches) only means
+// changes under that one directory go unnoticed, not that the whole
+// workspace should fail to start, so it's logged and walking continues
+// rather than aborting.
+func addRecursiveWatch(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			fmt.Printf("⚠️  Not watching %s: %v\n", path, err)
+		}
+		return nil
+	})
+}
+
+func (env *DevEnvironment) watchLoop(watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	var mu sync.Mutex
+	pending := map[string]struct{}{}
+	var debounce *time.Timer
+
+	// flush runs on time.AfterFunc's own goroutine, concurrently with the
+	// select loop below, so every access to pending/debounce has to go
+	// through mu — without it, a file event landing mid-flush is a
+	// concurrent map write.
+	flush := func() {
+		mu.Lock()
+		changed := pending
+		pending = map[string]struct{}{}
+		mu.Unlock()
+		for relPath := range changed {
+			env.handleFileChange(relPath)
+		}
+	}
+
+	for {
+		select {
+		case <-env.ctx.Done():
+			mu.Lock()
+			if debounce != nil {
+				debounce.Stop()
+			}
+			mu.Unlock()
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				watcher.Add(event.Name)
+				continue
+			}
+
+			relPath, err := filepath.Rel(env.tempDir, event.Name)
+			if err != nil {
+				relPath = event.Name
+			}
+
+			mu.Lock()
+			pending[filepath.ToSlash(relPath)] = struct{}{}
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.AfterFunc(debounceWindow, flush)
+			mu.Unlock()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("⚠️  Watcher error: %v\n", err)
+		}
+	}
+}
+
+// handleFileChange reacts to one coalesced path (relative to env.tempDir,
+// forward-slash separated): it detects a real database rewrite and fires
+// any trigger whose glob patterns match.
+func (env *DevEnvironment) handleFileChange(relPath string) {
+	if relPath == "project.db" {
+		if hash := env.getDatabaseHash(); hash != "" && hash != env.lastDBHash {
+			env.saveDatabaseState(hash)
+		}
+	}
+
+	for i := range env.triggers.Triggers {
+		trigger := &env.triggers.Triggers[i]
+		if trigger.matches(relPath) {
+			env.runTrigger(trigger)
+		}
+	}
+}
+
+// saveDatabaseState records a real database rewrite (gating repackBinary
+// the same way it always has) and, if a remote state backend is
+// configured, pushes the new bytes there too, using the previous hash for
+// optimistic-concurrency conflict detection.
+func (env *DevEnvironment) saveDatabaseState(newHash string) {
+	prevHash := env.lastDBHash
+	env.lastDBHash = newHash
+	env.dbModified = true
+
+	if env.state == nil {
+		return
+	}
+
+	data, err := os.ReadFile(env.dbPath)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read database for remote save: %v\n", err)
+		return
+	}
+
+	if err := env.state.Save(env.ctx, data, prevHash); err != nil {
+		if errors.Is(err, ErrStateConflict) {
+			fmt.Println("⚠️  Remote state was updated concurrently; local change not pushed")
+			return
+		}
+		fmt.Printf("⚠️  Failed to save database to state backend: %v\n", err)
+		return
+	}
+
+	fmt.Println("☁️  Pushed database update to remote state backend")
+}
+
+// runTrigger signals the current dev server process (if the trigger names
+// one), waits the configured delay, then re-runs the trigger's command,
+// replacing env.devServerCmd so a later trigger signals the new process.
+func (env *DevEnvironment) runTrigger(trigger *Trigger) {
+	fmt.Printf("🔁 Trigger %q fired, restarting dev server\n", trigger.Name)
+
+	if trigger.Signal != "" && env.devServerCmd != nil && env.devServerCmd.Process != nil {
+		if err := sendSignal(env.devServerCmd.Process, trigger.Signal); err != nil {
+			fmt.Printf("⚠️  Failed to signal dev server for trigger %q: %v\n", trigger.Name, err)
+		}
+	}
+
+	if trigger.Delay > 0 {
+		time.Sleep(trigger.Delay)
+	}
+
+	if trigger.Cmd == "" {
+		return
+	}
+
+	cmd := shellCommand(env.ctx, trigger.Cmd)
+	cmd.Dir = env.tempDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env.devServerEnv()
+
+	if err := cmd.Start(); err != nil {
+		fmt.Printf("⚠️  Trigger %q failed to run %q: %v\n", trigger.Name, trigger.Cmd, err)
+		return
+	}
+	env.devServerCmd = cmd
+	reapWhenDone(cmd)
+}
+
+// reapWhenDone waits for cmd in its own goroutine so its exit status gets
+// collected instead of leaking a zombie. Every trigger fire starts a new
+// dev server process and supersedes env.devServerCmd without ever waiting
+// on the one it replaced, so each Start() needs its own matching Wait()
+// rather than leaving it to whichever process happens to still be current
+// when the whole environment eventually shuts down.
+func reapWhenDone(cmd *exec.Cmd) {
+	go cmd.Wait()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// triggerConfigEntry is the payload entry kontained.yaml is embedded under,
+// stored alongside dev-server.ts.
+const triggerConfigEntry = "kontained.yaml"
+
+// Trigger is one fswatch/gosuv-style rule: when a changed file under
+// env.tempDir matches Patterns, Signal (if set) is sent to the running dev
+// server, then after Delay, Cmd is re-run.
+type Trigger struct {
+	Name     string
+	Patterns []string
+	Cmd      string
+	Signal   string
+	Delay    time.Duration
+}
+
+// TriggerConfig is the parsed form of kontained.yaml. A project without one
+// embedded gets a zero-value TriggerConfig, so the supervisor simply never
+// matches anything.
+type TriggerConfig struct {
+	Triggers []Trigger
+}
+
+type rawTriggerConfig struct {
+	Triggers []struct {
+		Name     string   `yaml:"name"`
+		Patterns []string `yaml:"patterns"`
+		Cmd      string   `yaml:"cmd"`
+		Signal   string   `yaml:"signal"`
+		Delay    string   `yaml:"delay"`
+	} `yaml:"triggers"`
+}
+
+// loadTriggerConfig reads and parses kontained.yaml from the payload, if
+// present.
+func loadTriggerConfig(payload *Payload) (*TriggerConfig, error) {
+	if !payload.Has(triggerConfigEntry) {
+		return &TriggerConfig{}, nil
+	}
+
+	data, err := payload.ReadEntry(triggerConfigEntry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from payload: %v", triggerConfigEntry, err)
+	}
+
+	var raw rawTriggerConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", triggerConfigEntry, err)
+	}
+
+	cfg := &TriggerConfig{Triggers: make([]Trigger, 0, len(raw.Triggers))}
+	for _, t := range raw.Triggers {
+		delay := time.Duration(0)
+		if t.Delay != "" {
+			d, err := time.ParseDuration(t.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("trigger %q has invalid delay %q: %v", t.Name, t.Delay, err)
+			}
+			delay = d
+		}
+
+		cfg.Triggers = append(cfg.Triggers, Trigger{
+			Name:     t.Name,
+			Patterns: t.Patterns,
+			Cmd:      t.Cmd,
+			Signal:   t.Signal,
+			Delay:    delay,
+		})
+	}
+
+	return cfg, nil
+}
+
+// matches reports whether relPath (relative to env.tempDir, forward-slash
+// separated) matches any of the trigger's glob patterns. Patterns may match
+// either the full relative path or just the file's base name.
+func (t *Trigger) matches(relPath string) bool {
+	for _, pattern := range t.Patterns {
+		if ok, _ := filepath.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
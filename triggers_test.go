@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestLoadTriggerConfigParsesYAML(t *testing.T) {
+	yaml := []byte(`
+triggers:
+  - name: restart-on-source-change
+    patterns:
+      - "src/**/*.ts"
+      - "*.json"
+    cmd: "npm run build"
+    signal: SIGTERM
+    delay: 250ms
+`)
+
+	payload, err := buildManifestPayload([]namedData{
+		{Name: triggerConfigEntry, Data: yaml},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildManifestPayload failed: %v", err)
+	}
+	p, err := OpenPayload(writeTestBinary(t, payload))
+	if err != nil {
+		t.Fatalf("OpenPayload failed: %v", err)
+	}
+
+	cfg, err := loadTriggerConfig(p)
+	if err != nil {
+		t.Fatalf("loadTriggerConfig failed: %v", err)
+	}
+
+	if len(cfg.Triggers) != 1 {
+		t.Fatalf("expected 1 trigger, got %d", len(cfg.Triggers))
+	}
+	tr := cfg.Triggers[0]
+	if tr.Name != "restart-on-source-change" || tr.Cmd != "npm run build" || tr.Signal != "SIGTERM" {
+		t.Errorf("unexpected trigger parsed: %+v", tr)
+	}
+	if tr.Delay.String() != "250ms" {
+		t.Errorf("expected delay 250ms, got %v", tr.Delay)
+	}
+}
+
+func TestLoadTriggerConfigWithoutEntryIsEmpty(t *testing.T) {
+	payload, err := buildManifestPayload([]namedData{
+		{Name: "project.db", Data: []byte{0x01}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildManifestPayload failed: %v", err)
+	}
+	p, err := OpenPayload(writeTestBinary(t, payload))
+	if err != nil {
+		t.Fatalf("OpenPayload failed: %v", err)
+	}
+
+	cfg, err := loadTriggerConfig(p)
+	if err != nil {
+		t.Fatalf("loadTriggerConfig failed: %v", err)
+	}
+	if len(cfg.Triggers) != 0 {
+		t.Errorf("expected no triggers when kontained.yaml is absent, got %d", len(cfg.Triggers))
+	}
+}
+
+func TestTriggerMatches(t *testing.T) {
+	tr := &Trigger{Patterns: []string{"src/**/*.ts", "*.json"}}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"package.json", true},
+		{"src/foo.ts", false},    // filepath.Match's "/" is literal, so "**/" needs a real extra segment
+		{"src/lib/foo.ts", true}, // ...which this path has
+		{"README.md", false},
+	}
+
+	for _, c := range cases {
+		if got := tr.matches(c.path); got != c.want {
+			t.Errorf("matches(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}